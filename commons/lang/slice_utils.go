@@ -1,29 +1,28 @@
 package lang
 
 import (
-	"reflect"
 	"errors"
 	"strconv"
 )
 
 const INDEX_NOT_FOUND = -1
 
-func Copy(source []interface{}) []interface{} {
-	if IsEmpty(source) {
+func Copy[T any](source []T) []T {
+	if len(source) == 0 {
 		return nil
 	}
-	dst := make([]interface{}, len(source))
+	dst := make([]T, len(source))
 	copy(dst, source)
 	return dst
 }
 
-func Add(source []interface{}, elem ...interface{}) []interface{} {
+func Add[T any](source []T, elem ...T) []T {
 	m := len(source)
 	n := len(elem)
-	if m + n == 0 {
+	if m+n == 0 {
 		return nil
 	}
-	dst := []interface{}(nil)
+	dst := []T(nil)
 	if m > 0 {
 		dst = append(dst, source...)
 	}
@@ -33,15 +32,15 @@ func Add(source []interface{}, elem ...interface{}) []interface{} {
 	return dst
 }
 
-func IsEmpty(source []interface{}) bool {
+func IsEmpty[T any](source []T) bool {
 	return len(source) == 0
 }
 
-func IsNotEmpty(source []interface{}) bool {
+func IsNotEmpty[T any](source []T) bool {
 	return !IsEmpty(source)
 }
 
-func IndexOf(source []interface{}, elem interface{}, startIndex int) int {
+func IndexOf[T comparable](source []T, elem T, startIndex int) int {
 	if IsEmpty(source) {
 		return INDEX_NOT_FOUND
 	}
@@ -49,14 +48,14 @@ func IndexOf(source []interface{}, elem interface{}, startIndex int) int {
 		startIndex = 0
 	}
 	for i := startIndex; i < len(source); i++ {
-		if reflect.DeepEqual(source[i], elem) {
+		if source[i] == elem {
 			return i
 		}
 	}
 	return INDEX_NOT_FOUND
 }
 
-func LastIndexOf(source []interface{}, elem interface{}, endIndex int) int {
+func LastIndexOf[T comparable](source []T, elem T, endIndex int) int {
 	if IsEmpty(source) {
 		return INDEX_NOT_FOUND
 	}
@@ -66,38 +65,86 @@ func LastIndexOf(source []interface{}, elem interface{}, endIndex int) int {
 		endIndex = len(source) - 1
 	}
 	for i := endIndex; i >= 0; i-- {
-		if reflect.DeepEqual(source[i], elem) {
+		if source[i] == elem {
 			return i
 		}
 	}
 	return INDEX_NOT_FOUND
 }
 
-func Contains(source []interface{}, elem interface{}) bool {
+func Contains[T comparable](source []T, elem T) bool {
 	return IndexOf(source, elem, 0) > INDEX_NOT_FOUND
 }
 
-func Insert(index int, source []interface{}, elem ...interface{}) []interface{} {
-	if IsEmpty(source) {
-		return nil
-	}
-	if len(elem) == 0 {
+// Insert splices elems into source starting at index, shifting the
+// remaining elements right. index == len(source) appends elems.
+func Insert[T any](index int, source []T, elems ...T) []T {
+	if len(elems) == 0 {
 		return Copy(source)
 	}
-	if index < 0 || index >= len(source) {
-		panic(errors.New("index out of bounds! Index: " + strconv.Itoa(index) + ", Length: " + strconv.Itoa(len(source))))
+	if index < 0 || index > len(source) {
+		panic(indexOutOfBounds(index, len(source)))
 	}
-	dst := append([]interface{}(nil), source[:index])
-	dst = append(dst, append([]interface{}{elem}, source[index:]...)...)
+	dst := make([]T, 0, len(source)+len(elems))
+	dst = append(dst, source[:index]...)
+	dst = append(dst, elems...)
+	dst = append(dst, source[index:]...)
 	return dst
 }
 
-func Remove(source []interface{}, index int) interface{} {
+// Remove deletes the element at index, returning the removed value and
+// the resulting slice.
+func Remove[T any](source []T, index int) (T, []T) {
+	if index < 0 || index >= len(source) {
+		panic(indexOutOfBounds(index, len(source)))
+	}
+	removed := source[index]
+	dst := make([]T, 0, len(source)-1)
+	dst = append(dst, source[:index]...)
+	dst = append(dst, source[index+1:]...)
+	return removed, dst
+}
+
+// Equaler compares a and b for equality. It lets IndexOfFunc/ContainsFunc
+// work with element types that are not comparable with ==.
+type Equaler[T any] func(a, b T) bool
+
+func IndexOfFunc[T any](source []T, elem T, startIndex int, eq Equaler[T]) int {
 	if IsEmpty(source) {
-		return nil
+		return INDEX_NOT_FOUND
 	}
-	if index < 0 || index >= len(source) {
-		panic(errors.New("index out of bounds! Index: " + strconv.Itoa(index) + ", Length: " + strconv.Itoa(len(source))))
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	for i := startIndex; i < len(source); i++ {
+		if eq(source[i], elem) {
+			return i
+		}
+	}
+	return INDEX_NOT_FOUND
+}
+
+func LastIndexOfFunc[T any](source []T, elem T, endIndex int, eq Equaler[T]) int {
+	if IsEmpty(source) {
+		return INDEX_NOT_FOUND
+	}
+	if endIndex < 0 {
+		return INDEX_NOT_FOUND
+	} else if endIndex >= len(source) {
+		endIndex = len(source) - 1
 	}
-	return append(source[:index],source[index+1:]...)
-}
\ No newline at end of file
+	for i := endIndex; i >= 0; i-- {
+		if eq(source[i], elem) {
+			return i
+		}
+	}
+	return INDEX_NOT_FOUND
+}
+
+func ContainsFunc[T any](source []T, elem T, eq Equaler[T]) bool {
+	return IndexOfFunc(source, elem, 0, eq) > INDEX_NOT_FOUND
+}
+
+func indexOutOfBounds(index, length int) error {
+	return errors.New("index out of bounds! Index: " + strconv.Itoa(index) + ", Length: " + strconv.Itoa(length))
+}
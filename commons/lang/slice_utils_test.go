@@ -0,0 +1,126 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		src   []int
+		elems []int
+		want  []int
+	}{
+		{"middle", 1, []int{1, 2, 3}, []int{9}, []int{1, 9, 2, 3}},
+		{"multiple elems", 0, []int{1, 2}, []int{8, 9}, []int{8, 9, 1, 2}},
+		{"append at len", 2, []int{1, 2}, []int{3}, []int{1, 2, 3}},
+		{"no elems is a copy", 1, []int{1, 2}, nil, []int{1, 2}},
+		{"append into empty source", 0, nil, []int{1}, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Insert(tt.index, tt.src, tt.elems...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Insert(%d, %v, %v) = %v, want %v", tt.index, tt.src, tt.elems, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertOutOfBounds(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for out-of-bounds index")
+		}
+	}()
+	Insert(3, []int{1, 2}, 9)
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       []string
+		index     int
+		wantElem  string
+		wantSlice []string
+	}{
+		{"first", []string{"a", "b", "c"}, 0, "a", []string{"b", "c"}},
+		{"middle", []string{"a", "b", "c"}, 1, "b", []string{"a", "c"}},
+		{"last", []string{"a", "b", "c"}, 2, "c", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotElem, gotSlice := Remove(tt.src, tt.index)
+			if gotElem != tt.wantElem {
+				t.Errorf("Remove(%v, %d) elem = %v, want %v", tt.src, tt.index, gotElem, tt.wantElem)
+			}
+			if !reflect.DeepEqual(gotSlice, tt.wantSlice) {
+				t.Errorf("Remove(%v, %d) slice = %v, want %v", tt.src, tt.index, gotSlice, tt.wantSlice)
+			}
+		})
+	}
+}
+
+func TestRemoveOutOfBounds(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for out-of-bounds index")
+		}
+	}()
+	Remove([]int{1, 2}, 2)
+}
+
+func TestIndexOf(t *testing.T) {
+	src := []int{1, 2, 3, 2}
+	if got := IndexOf(src, 2, 0); got != 1 {
+		t.Errorf("IndexOf = %d, want 1", got)
+	}
+	if got := IndexOf(src, 2, 2); got != 3 {
+		t.Errorf("IndexOf with startIndex = %d, want 3", got)
+	}
+	if got := IndexOf(src, 9, 0); got != INDEX_NOT_FOUND {
+		t.Errorf("IndexOf missing elem = %d, want %d", got, INDEX_NOT_FOUND)
+	}
+}
+
+func TestContains(t *testing.T) {
+	if !Contains([]string{"a", "b"}, "b") {
+		t.Error("Contains should find present element")
+	}
+	if Contains([]string{"a", "b"}, "c") {
+		t.Error("Contains should not find absent element")
+	}
+}
+
+func TestContainsFunc(t *testing.T) {
+	type point struct{ x, y int }
+	src := []point{{1, 1}, {2, 2}}
+	eq := func(a, b point) bool { return a.x == b.x && a.y == b.y }
+	if !ContainsFunc(src, point{2, 2}, eq) {
+		t.Error("ContainsFunc should find present element")
+	}
+	if ContainsFunc(src, point{3, 3}, eq) {
+		t.Error("ContainsFunc should not find absent element")
+	}
+}
+
+func TestCopyAndAdd(t *testing.T) {
+	src := []int{1, 2, 3}
+	cp := Copy(src)
+	if !reflect.DeepEqual(cp, src) {
+		t.Errorf("Copy = %v, want %v", cp, src)
+	}
+	cp[0] = 99
+	if src[0] == 99 {
+		t.Error("Copy should not alias the source slice")
+	}
+
+	if got := Add([]int{1, 2}, 3, 4); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("Add = %v, want [1 2 3 4]", got)
+	}
+	if got := Add[int](nil); got != nil {
+		t.Errorf("Add with no elements = %v, want nil", got)
+	}
+}
@@ -0,0 +1,109 @@
+package inet
+
+import (
+	"net"
+	"time"
+)
+
+// ConnParams bundles the per-connection tuning applied to every accepted
+// *net.TCPConn.
+type ConnParams struct {
+	ReadBufferSize  int
+	WriteBufferSize int
+	KeepAlive       time.Duration
+	TCPNoDelay      bool
+	LingerSeconds   int
+	IdleTimeout     time.Duration
+}
+
+// KeepAlive sets the TCP keepalive period applied to every accepted
+// connection. Zero (the default) leaves the connection's keepalive setting
+// as its listener configured it (net.Listen enables keepalive by default).
+func KeepAlive(d time.Duration) ServerOption {
+	return func(o *options) {
+		o.keepAlive = d
+	}
+}
+
+// TCPNoDelay disables Nagle's algorithm on every accepted connection.
+func TCPNoDelay(enabled bool) ServerOption {
+	return func(o *options) {
+		o.tcpNoDelay = enabled
+	}
+}
+
+// Linger sets SO_LINGER (in seconds) on every accepted connection. Zero
+// (the default) leaves the platform's default linger behavior in place.
+func Linger(seconds int) ServerOption {
+	return func(o *options) {
+		o.lingerSeconds = seconds
+	}
+}
+
+// IdleTimeout closes a connection that has seen no frame read or write
+// within the given duration, so a slow-loris connection cannot sit on a
+// handler slot indefinitely. Zero (the default) disables the watchdog.
+func IdleTimeout(d time.Duration) ServerOption {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// connParams assembles the ConnParams to apply to newly accepted
+// connections from the server's configured options.
+func (s *Server) connParams() ConnParams {
+	return ConnParams{
+		ReadBufferSize:  s.opts.readBufferSize,
+		WriteBufferSize: s.opts.writeBufferSize,
+		KeepAlive:       s.opts.keepAlive,
+		TCPNoDelay:      s.opts.tcpNoDelay,
+		LingerSeconds:   s.opts.lingerSeconds,
+		IdleTimeout:     s.opts.idleTimeout,
+	}
+}
+
+// applyConnParams tunes conn according to p. Non-TCP connections are left
+// untouched.
+func applyConnParams(conn net.Conn, p ConnParams) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if p.KeepAlive > 0 {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(p.KeepAlive)
+	}
+	if p.TCPNoDelay {
+		tcpConn.SetNoDelay(true)
+	}
+	if p.ReadBufferSize > 0 {
+		tcpConn.SetReadBuffer(p.ReadBufferSize)
+	}
+	if p.WriteBufferSize > 0 {
+		tcpConn.SetWriteBuffer(p.WriteBufferSize)
+	}
+	if p.LingerSeconds != 0 {
+		tcpConn.SetLinger(p.LingerSeconds)
+	}
+}
+
+// idleWatchdog closes conn if it sees no traffic within idleTimeout of the
+// last touch signal. It runs until done is closed or the timeout fires.
+func idleWatchdog(conn net.Conn, idleTimeout time.Duration, done <-chan struct{}, touch <-chan struct{}) {
+	timer := time.NewTimer(idleTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-touch:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(idleTimeout)
+		case <-timer.C:
+			conn.Close()
+			return
+		case <-done:
+			return
+		}
+	}
+}
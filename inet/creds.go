@@ -0,0 +1,80 @@
+package inet
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// AuthInfo carries security information about an established connection,
+// e.g. the TLS state negotiated during the handshake.
+type AuthInfo interface {
+	AuthType() string
+}
+
+// Credentials defines server-side transport security, applied to every
+// accepted connection before the framing loop starts.
+type Credentials interface {
+	// ServerHandshake performs the security handshake over rawConn,
+	// returning a wrapped connection ready for framed I/O along with
+	// information about the authenticated peer.
+	ServerHandshake(rawConn net.Conn) (net.Conn, AuthInfo, error)
+}
+
+// Creds returns a ServerOption that sets the transport credentials used to
+// secure every accepted connection.
+func Creds(c Credentials) ServerOption {
+	return func(o *options) {
+		o.creds = c
+	}
+}
+
+type tlsCreds struct {
+	config *tls.Config
+}
+
+// NewTLS wraps config as server-side Credentials. SNI-based multi-cert
+// selection and mutual TLS are configured on config itself (Certificates
+// or GetCertificate, and ClientCAs/ClientAuth respectively), exactly as
+// with the standard library's crypto/tls.
+func NewTLS(config *tls.Config) Credentials {
+	return &tlsCreds{config: config.Clone()}
+}
+
+func (c *tlsCreds) ServerHandshake(rawConn net.Conn) (net.Conn, AuthInfo, error) {
+	conn := tls.Server(rawConn, c.config)
+	if err := conn.Handshake(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, tlsInfo{state: conn.ConnectionState()}, nil
+}
+
+// tlsInfo implements AuthInfo for a completed TLS handshake.
+type tlsInfo struct {
+	state tls.ConnectionState
+}
+
+func (t tlsInfo) AuthType() string {
+	return "tls"
+}
+
+// State returns the negotiated TLS connection state.
+func (t tlsInfo) State() tls.ConnectionState {
+	return t.state
+}
+
+type authInfoKey struct{}
+
+// NewContextWithAuthInfo returns a copy of ctx carrying ai, retrievable
+// later with AuthInfoFromContext.
+func NewContextWithAuthInfo(ctx context.Context, ai AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey{}, ai)
+}
+
+// AuthInfoFromContext extracts the AuthInfo stored by the transport
+// credentials handshake, if any.
+func AuthInfoFromContext(ctx context.Context) (AuthInfo, bool) {
+	ai, ok := ctx.Value(authInfoKey{}).(AuthInfo)
+	return ai, ok
+}
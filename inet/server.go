@@ -10,11 +10,17 @@ import (
 	"errors"
 	"runtime"
 	"fmt"
+	"io"
+	"encoding/binary"
 )
 
 const (
 	defaultServerMaxReceiveMessageSize = 1024 * 1024 * 4
 	defaultServerMaxSendMessageSize    = math.MaxInt32
+
+	// frameHeaderLen is the size in bytes of the fixed-size portion of a
+	// frame: a u32 total length followed by a u16 header length.
+	frameHeaderLen = 4 + 2
 )
 
 type options struct {
@@ -25,6 +31,16 @@ type options struct {
 	initialConnWindowSize int32
 	writeBufferSize       int
 	readBufferSize        int
+	readTimeout           time.Duration
+	writeTimeout          time.Duration
+	acceptTimeout         time.Duration
+	handler               Handler
+	middlewares           []Middleware
+	creds                 Credentials
+	keepAlive             time.Duration
+	tcpNoDelay            bool
+	lingerSeconds         int
+	idleTimeout           time.Duration
 }
 
 var defaultServerOptions = options {
@@ -32,17 +48,49 @@ var defaultServerOptions = options {
 	maxSendMessageSize:    defaultServerMaxSendMessageSize,
 }
 
-type Connection struct {
-	conn net.Listener
-	server Server
-	userId uint64
+// Request is a single decoded frame handed to a Handler.
+type Request struct {
+	Header []byte
+	Body   []byte
+}
+
+// Response is written back to the client using the same framing as Request.
+type Response struct {
+	Header []byte
+	Body   []byte
+}
+
+// Handler dispatches a decoded Request and produces a Response.
+type Handler interface {
+	Handle(ctx context.Context, req *Request) (*Response, error)
+}
+
+// HandlerFunc adapts a plain function to the Handler interface.
+type HandlerFunc func(ctx context.Context, req *Request) (*Response, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// Middleware wraps a Handler to produce another Handler, e.g. for auth or
+// rate limiting. Middlewares are applied in the order they are registered,
+// so the first middleware is the outermost one.
+type Middleware func(Handler) Handler
+
+// chain composes a base Handler with the given middlewares, outermost first.
+func chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
 }
 
 type Server struct {
 	opts options
 	mu     sync.Mutex // guards following
 	lis    map[net.Listener]bool
-	connections map[net.Listener]Connection
+	connections map[net.Conn]bool
+	handler Handler
 	serve  bool
 	drain  bool
 	ctx    context.Context
@@ -51,6 +99,7 @@ type Server struct {
 	// and all the transport goes away.
 	cv     *sync.Cond
 	events trace.EventLog
+	onShutdown []func()
 }
 
 type ServerOption func(*options)
@@ -101,6 +150,47 @@ func MaxConcurrentStreams(n uint32) ServerOption {
 	}
 }
 
+// ReadTimeout sets the deadline applied before each frame read on an
+// accepted connection. Zero (the default) means no deadline.
+func ReadTimeout(d time.Duration) ServerOption {
+	return func(o *options) {
+		o.readTimeout = d
+	}
+}
+
+// WriteTimeout sets the deadline applied before each response write on an
+// accepted connection. Zero (the default) means no deadline.
+func WriteTimeout(d time.Duration) ServerOption {
+	return func(o *options) {
+		o.writeTimeout = d
+	}
+}
+
+// WithHandler registers the Handler that decoded Requests are dispatched
+// to. Without one, handleRawConn rejects every frame it reads.
+func WithHandler(h Handler) ServerOption {
+	return func(o *options) {
+		o.handler = h
+	}
+}
+
+// WithMiddleware appends middlewares around the registered Handler, in
+// the order they should run (the first one is outermost).
+func WithMiddleware(mw ...Middleware) ServerOption {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, mw...)
+	}
+}
+
+// AcceptTimeout bounds how long a blocking Accept call runs before the
+// accept loop re-checks s.ctx, making Stop/GracefulStop responsive even
+// when no connections arrive. Zero (the default) disables the deadline.
+func AcceptTimeout(d time.Duration) ServerOption {
+	return func(o *options) {
+		o.acceptTimeout = d
+	}
+}
+
 type ConnectHandler interface {
 	handleConnect(conn net.Conn)
 }
@@ -109,6 +199,13 @@ var (
 	// ErrServerStopped indicates that the operation is now illegal because of
 	// the server being stopped.
 	ErrServerStopped = errors.New("demon-ring: the server has been stopped")
+
+	// errNoHandler indicates that a frame was decoded but the server has
+	// no Handler to dispatch it to.
+	errNoHandler = errors.New("demon-ring: no handler registered")
+
+	// errMessageTooLarge indicates a frame exceeded the configured max size.
+	errMessageTooLarge = errors.New("demon-ring: message too large")
 )
 
 func NewServer(opt ...ServerOption) *Server {
@@ -119,7 +216,10 @@ func NewServer(opt ...ServerOption) *Server {
 	s := &Server{
 		lis:   make(map[net.Listener]bool),
 		opts:  opts,
-		connections: make(map[net.Listener]Connection),
+		connections: make(map[net.Conn]bool),
+	}
+	if opts.handler != nil {
+		s.handler = chain(opts.handler, opts.middlewares...)
 	}
 	s.cv = sync.NewCond(&s.mu)
 	s.ctx, s.cancel = context.WithCancel(context.Background())
@@ -131,32 +231,48 @@ func NewServer(opt ...ServerOption) *Server {
 func (s *Server) ServeTCP(port string, handler ConnectHandler) {
 	defer func() {
 		if err := recover(); err != nil {
-			// 处理全局异常
+			s.events.Errorf("demon-ring: ServeTCP panic: %v", err)
 		}
 	}()
 
 	ln, err := net.Listen("tcp", port)
 	if err != nil {
-		// 处理全局异常
+		s.events.Errorf("demon-ring: ServeTCP failed to listen on %q: %v", port, err)
+		return
 	}
-	defer ln.Close()
 
-	// 配置连接保持属性
-	if tcpl, ok :=  ln.(*net.TCPListener); ok {
-		// Wrap TCP listener to enable TCP keep-alive
-		ln, err := tcpl.AcceptTCP()
-		if err != nil {
-			// 处理异常
-			return
-		}
-		ln.SetKeepAlive(true)
-		ln.SetKeepAlivePeriod(30 * time.Second)
+	s.mu.Lock()
+	if s.lis == nil {
+		s.mu.Unlock()
+		ln.Close()
+		return
 	}
+	s.lis[ln] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.lis != nil && s.lis[ln] {
+			ln.Close()
+			delete(s.lis, ln)
+		}
+		s.mu.Unlock()
+	}()
 
 	var tempDelay time.Duration
 	for {
+		if s.opts.acceptTimeout > 0 {
+			if dl, ok := ln.(interface{ SetDeadline(time.Time) error }); ok {
+				dl.SetDeadline(time.Now().Add(s.opts.acceptTimeout))
+			}
+		}
 		conn, err := ln.Accept()
 		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
 				if tempDelay == 0 {
 					tempDelay = 5 * time.Millisecond
@@ -171,8 +287,18 @@ func (s *Server) ServeTCP(port string, handler ConnectHandler) {
 			}
 			return
 		}
+		// 配置连接保持属性 — applied to the actually-accepted conn, not
+		// a throwaway AcceptTCP() result outside the loop.
+		applyConnParams(conn, s.connParams())
+		if !s.addConn(conn) {
+			conn.Close()
+			continue
+		}
 		// 启动新的chan处理客户端连接
-		go handler.handleConnect(conn)
+		go func(c net.Conn) {
+			defer s.removeConn(c)
+			handler.handleConnect(c)
+		}(conn)
 	}
 }
 
@@ -199,8 +325,19 @@ func (s *Server) Serve(lis net.Listener) error {
 	var tempDelay time.Duration // how long to sleep on accept failure
 
 	for {
+		if s.opts.acceptTimeout > 0 {
+			if dl, ok := lis.(interface{ SetDeadline(time.Time) error }); ok {
+				dl.SetDeadline(time.Now().Add(s.opts.acceptTimeout))
+			}
+		}
 		rawConn, err := lis.Accept()
 		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
 			if ne, ok := err.(interface {
 				Temporary() bool
 			}); ok && ne.Temporary() {
@@ -235,6 +372,256 @@ func (s *Server) Serve(lis net.Listener) error {
 	}
 }
 
-func (s *Server) handleRawConn(rawConn net.Conn) {
+// RegisterOnShutdown registers a function to be called when Stop or
+// GracefulStop is invoked, after listeners are closed. This is meant to
+// give applications a chance to decelerate and drain their own state,
+// mirroring net/http.Server.RegisterOnShutdown.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.mu.Lock()
+	s.onShutdown = append(s.onShutdown, f)
+	s.mu.Unlock()
+}
+
+// Stop stops the server, closing every tracked listener and every active
+// connection immediately. Pending handlers are not given a chance to
+// finish; use GracefulStop for that.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	listeners := s.lis
+	s.lis = nil
+	conns := s.connections
+	s.connections = nil
+	s.drain = true
+	s.cancel()
+	onShutdown := s.onShutdown
+	s.mu.Unlock()
+
+	for lis := range listeners {
+		lis.Close()
+	}
+	for conn := range conns {
+		conn.Close()
+	}
+	for _, f := range onShutdown {
+		f()
+	}
+
+	s.mu.Lock()
+	s.cv.Broadcast()
+	s.mu.Unlock()
+}
+
+// GracefulStop stops the server from accepting new connections and
+// RPCs and blocks until all the pending RPCs are finished and all the
+// transport goes away, then runs the registered shutdown hooks.
+func (s *Server) GracefulStop() {
+	s.mu.Lock()
+	if s.drain {
+		s.mu.Unlock()
+		return
+	}
+	s.drain = true
+	listeners := s.lis
+	s.lis = nil
+	s.cancel()
+	s.mu.Unlock()
+
+	for lis := range listeners {
+		lis.Close()
+	}
+
+	s.mu.Lock()
+	for len(s.connections) > 0 {
+		s.cv.Wait()
+	}
+	onShutdown := s.onShutdown
+	s.mu.Unlock()
+
+	for _, f := range onShutdown {
+		f()
+	}
+}
+
+// addConn tracks rawConn so it can be closed or waited on by Stop/GracefulStop.
+func (s *Server) addConn(rawConn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.connections == nil {
+		return false
+	}
+	s.connections[rawConn] = true
+	return true
+}
+
+// removeConn stops tracking rawConn, waking any GracefulStop waiting on
+// the connection count to drain.
+func (s *Server) removeConn(rawConn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.connections, rawConn)
+	s.cv.Broadcast()
+}
+
+// handleRawConn runs the per-connection read loop: it parses length-prefixed
+// frames off the connection, dispatches each as a Request through
+// s.handler, and writes back the resulting Response using the same wire
+// format.
+func (s *Server) handleRawConn(acceptedConn net.Conn) {
+	applyConnParams(acceptedConn, s.connParams())
+
+	// Track the connection before the (potentially slow or stalled) TLS
+	// handshake below, so a client that opens a socket and never completes
+	// the handshake is still reachable and force-closeable from Stop().
+	if !s.addConn(acceptedConn) {
+		acceptedConn.Close()
+		return
+	}
+	defer s.removeConn(acceptedConn)
+
+	rawConn := acceptedConn
+	defer func() { rawConn.Close() }()
 
-}
\ No newline at end of file
+	ctx := s.ctx
+
+	if s.opts.creds != nil {
+		handshakeDeadline := s.opts.readTimeout
+		if s.opts.writeTimeout > handshakeDeadline {
+			handshakeDeadline = s.opts.writeTimeout
+		}
+		if handshakeDeadline > 0 {
+			rawConn.SetDeadline(time.Now().Add(handshakeDeadline))
+		}
+		conn, authInfo, err := s.opts.creds.ServerHandshake(rawConn)
+		if err != nil {
+			return
+		}
+		if handshakeDeadline > 0 {
+			conn.SetDeadline(time.Time{})
+		}
+		rawConn = conn
+		ctx = NewContextWithAuthInfo(ctx, authInfo)
+	}
+
+	idleTimeout := s.opts.idleTimeout
+	var touch chan struct{}
+	if idleTimeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		touch = make(chan struct{}, 1)
+		go idleWatchdog(rawConn, idleTimeout, done, touch)
+	}
+	notifyActivity := func() {
+		if touch == nil {
+			return
+		}
+		select {
+		case touch <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		if s.opts.readTimeout > 0 {
+			rawConn.SetReadDeadline(time.Now().Add(s.opts.readTimeout))
+		}
+		req, err := readRequest(rawConn, s.opts.maxReceiveMessageSize)
+		if err != nil {
+			return
+		}
+		if s.ctx.Err() != nil {
+			// The server is stopping: accept no new requests.
+			return
+		}
+		notifyActivity()
+
+		resp, err := s.dispatch(ctx, req)
+		if err != nil {
+			return
+		}
+
+		if s.opts.writeTimeout > 0 {
+			rawConn.SetWriteDeadline(time.Now().Add(s.opts.writeTimeout))
+		}
+		if err := s.writeResponse(rawConn, resp); err != nil {
+			return
+		}
+		notifyActivity()
+	}
+}
+
+func (s *Server) dispatch(ctx context.Context, req *Request) (*Response, error) {
+	if s.handler == nil {
+		return nil, errNoHandler
+	}
+	return s.handler.Handle(ctx, req)
+}
+
+func (s *Server) writeResponse(conn net.Conn, resp *Response) error {
+	return writeFrame(conn, resp, s.opts.maxSendMessageSize)
+}
+
+// readRequest reads one length-prefixed frame off conn: a u32 total length,
+// a u16 header length, the header bytes, and finally the body bytes.
+func readRequest(conn net.Conn, maxReceiveMessageSize int) (*Request, error) {
+	var lenBuf [frameHeaderLen]byte
+	if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	totalLen := binary.BigEndian.Uint32(lenBuf[0:4])
+	headerLen := binary.BigEndian.Uint16(lenBuf[4:6])
+
+	if maxReceiveMessageSize > 0 && int(totalLen) > maxReceiveMessageSize {
+		return nil, errMessageTooLarge
+	}
+	if int(headerLen) > int(totalLen) {
+		return nil, errors.New("demon-ring: frame header length exceeds total length")
+	}
+
+	header := make([]byte, headerLen)
+	if headerLen > 0 {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+	}
+
+	bodyLen := int(totalLen) - int(headerLen)
+	body := make([]byte, bodyLen)
+	if bodyLen > 0 {
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Request{Header: header, Body: body}, nil
+}
+
+// WriteResponse writes resp to conn using the same length-prefixed frame
+// format as readRequest: a u32 total length, a u16 header length, the
+// header bytes, and the body bytes.
+func WriteResponse(conn net.Conn, resp *Response) error {
+	return writeFrame(conn, resp, defaultServerMaxSendMessageSize)
+}
+
+// writeFrame is the shared implementation behind WriteResponse and
+// Server.writeResponse; maxSendMessageSize <= 0 disables the size check.
+func writeFrame(conn net.Conn, resp *Response, maxSendMessageSize int) error {
+	if resp == nil {
+		resp = &Response{}
+	}
+	totalLen := len(resp.Header) + len(resp.Body)
+	if maxSendMessageSize > 0 && totalLen > maxSendMessageSize {
+		return errMessageTooLarge
+	}
+	if len(resp.Header) > math.MaxUint16 {
+		return errors.New("demon-ring: response header too large")
+	}
+
+	buf := make([]byte, frameHeaderLen+totalLen)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(totalLen))
+	binary.BigEndian.PutUint16(buf[4:6], uint16(len(resp.Header)))
+	copy(buf[frameHeaderLen:], resp.Header)
+	copy(buf[frameHeaderLen+len(resp.Header):], resp.Body)
+
+	_, err := conn.Write(buf)
+	return err
+}
@@ -0,0 +1,197 @@
+package inet
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeConnectHandler struct{}
+
+func (fakeConnectHandler) handleConnect(conn net.Conn) {}
+
+func TestServeTCPReturnsOnListenFailure(t *testing.T) {
+	s := NewServer()
+
+	done := make(chan struct{})
+	go func() {
+		s.ServeTCP("not-a-valid-port", fakeConnectHandler{})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ServeTCP did not return after a net.Listen failure")
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	resp := &Response{Header: []byte("hdr"), Body: []byte("body")}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- WriteResponse(client, resp)
+	}()
+
+	req, err := readRequest(server, 0)
+	if err != nil {
+		t.Fatalf("readRequest: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+
+	if !bytes.Equal(req.Header, resp.Header) {
+		t.Errorf("Header = %q, want %q", req.Header, resp.Header)
+	}
+	if !bytes.Equal(req.Body, resp.Body) {
+		t.Errorf("Body = %q, want %q", req.Body, resp.Body)
+	}
+}
+
+func TestFrameRoundTripEmptyHeader(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	resp := &Response{Body: []byte("body-only")}
+	go WriteResponse(client, resp)
+
+	req, err := readRequest(server, 0)
+	if err != nil {
+		t.Fatalf("readRequest: %v", err)
+	}
+	if len(req.Header) != 0 {
+		t.Errorf("Header = %q, want empty", req.Header)
+	}
+	if !bytes.Equal(req.Body, resp.Body) {
+		t.Errorf("Body = %q, want %q", req.Body, resp.Body)
+	}
+}
+
+func TestReadRequestRejectsOversizedFrame(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go WriteResponse(client, &Response{Body: []byte("0123456789")})
+
+	if _, err := readRequest(server, 4); err != errMessageTooLarge {
+		t.Errorf("readRequest error = %v, want %v", err, errMessageTooLarge)
+	}
+}
+
+func TestWriteFrameRejectsOversizedFrame(t *testing.T) {
+	_, server := net.Pipe()
+	defer server.Close()
+
+	err := writeFrame(server, &Response{Body: []byte("0123456789")}, 4)
+	if err != errMessageTooLarge {
+		t.Errorf("writeFrame error = %v, want %v", err, errMessageTooLarge)
+	}
+}
+
+func TestGracefulStopWaitsForActiveConn(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := HandlerFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		close(started)
+		<-release
+		return &Response{}, nil
+	})
+	s := NewServer(WithHandler(h))
+	go s.Serve(lis)
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := WriteResponse(conn, &Response{Body: []byte("ping")}); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	<-started
+	// The handler's response is never read by the test; close the conn so
+	// that, once the handler returns, handleRawConn's next frame read sees
+	// the closed connection and exits instead of blocking.
+	conn.Close()
+
+	stopDone := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopDone)
+	}()
+
+	select {
+	case <-stopDone:
+		t.Fatal("GracefulStop returned before the active handler finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case <-stopDone:
+	case <-time.After(time.Second):
+		t.Fatal("GracefulStop did not return after the handler finished")
+	}
+}
+
+func TestStopClosesActiveConnImmediately(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	defer close(block)
+	h := HandlerFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		close(started)
+		<-block
+		return &Response{}, nil
+	})
+	s := NewServer(WithHandler(h))
+	go s.Serve(lis)
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := WriteResponse(conn, &Response{Body: []byte("ping")}); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	<-started
+
+	stopDone := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopDone)
+	}()
+
+	select {
+	case <-stopDone:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return promptly while a handler was still running")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err == nil {
+		t.Error("expected the client connection to be closed by Stop")
+	}
+}